@@ -1,7 +1,6 @@
 package query
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 	"text/scanner"
@@ -15,10 +14,22 @@ type Document struct {
 }
 
 type Operation struct {
-	Type      OperationType
-	Name      string
-	Variables map[string]*VariableDef
-	SelSet    *SelectionSet
+	Type       OperationType
+	Name       string
+	Variables  map[string]*VariableDef
+	Directives DirectiveList
+	SelSet     *SelectionSet
+	Loc        Loc
+}
+
+// Loc is the line/column of the next token at the point a node's parser
+// starts, used to populate the "locations" entry of a spec-compliant error
+// response. For an operation or fragment that begins with a keyword
+// ("query", "mutation", "subscription", "fragment"), that is the token
+// after the keyword, not the keyword itself.
+type Loc struct {
+	Line   int
+	Column int
 }
 
 type OperationType int
@@ -26,17 +37,43 @@ type OperationType int
 const (
 	Query OperationType = iota
 	Mutation
+	Subscription
 )
 
 type VariableDef struct {
+	Name       string
+	Type       Type
+	Default    Value
+	Directives DirectiveList
+	Loc        Loc
+}
+
+type Type interface {
+	isType()
+}
+
+type NamedType struct {
 	Name string
-	Type string
 }
 
+type ListType struct {
+	OfType Type
+}
+
+type NonNullType struct {
+	OfType Type
+}
+
+func (NamedType) isType()   {}
+func (ListType) isType()    {}
+func (NonNullType) isType() {}
+
 type Fragment struct {
-	Name   string
-	On     string
-	SelSet *SelectionSet
+	Name       string
+	On         string
+	Directives DirectiveList
+	SelSet     *SelectionSet
+	Loc        Loc
 }
 
 type SelectionSet struct {
@@ -50,19 +87,50 @@ type Selection interface {
 type Field struct {
 	Alias      string
 	Name       string
-	Arguments  map[string]Value
-	Directives map[string]*Directive
+	Arguments  ArgumentList
+	Directives DirectiveList
 	SelSet     *SelectionSet
+	Loc        Loc
+}
+
+type Argument struct {
+	Name  string
+	Value Value
+	Loc   Loc
+}
+
+type ArgumentList []Argument
+
+func (l ArgumentList) Get(name string) (Value, bool) {
+	for _, arg := range l {
+		if arg.Name == name {
+			return arg.Value, true
+		}
+	}
+	return nil, false
 }
 
 type Directive struct {
 	Name      string
-	Arguments map[string]Value
+	Arguments ArgumentList
+	Loc       Loc
+}
+
+type DirectiveList []*Directive
+
+func (l DirectiveList) Get(name string) *Directive {
+	for _, d := range l {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
 }
 
 type FragmentSpread struct {
 	Name       string
-	Directives map[string]*Directive
+	Directives DirectiveList
+	Loc        Loc
 }
 
 func (Field) isSelection()          {}
@@ -78,21 +146,58 @@ type Variable struct {
 
 type Literal struct {
 	Value interface{}
+	Type  ValueType
 }
 
-func (Variable) isValue() {}
-func (Literal) isValue()  {}
+type NullValue struct{}
 
-func Parse(queryString string) (res *Document, errRes error) {
+type ListValue struct {
+	Values []Value
+}
+
+type ObjectValue struct {
+	Fields map[string]Value
+}
+
+func (Variable) isValue()    {}
+func (Literal) isValue()     {}
+func (NullValue) isValue()   {}
+func (ListValue) isValue()   {}
+func (ObjectValue) isValue() {}
+
+// QueryError is the GraphQL spec's error shape: a message plus the
+// locations and response path that produced it.
+type QueryError struct {
+	Message   string        `json:"message"`
+	Locations []Loc         `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+}
+
+func (err *QueryError) Error() string {
+	return err.Message
+}
+
+func errorf(loc Loc, format string, a ...interface{}) *QueryError {
+	return &QueryError{
+		Message:   fmt.Sprintf(format, a...),
+		Locations: []Loc{loc},
+	}
+}
+
+func Parse(queryString string) (res *Document, errRes *QueryError) {
 	sc := &scanner.Scanner{
-		Mode: scanner.ScanIdents | scanner.ScanFloats | scanner.ScanStrings,
+		Mode: scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats | scanner.ScanStrings,
 	}
 	sc.Init(strings.NewReader(queryString))
 
 	defer func() {
 		if err := recover(); err != nil {
 			if err, ok := err.(lexer.SyntaxError); ok {
-				errRes = errors.New(string(err))
+				errRes = &QueryError{Message: string(err)}
+				return
+			}
+			if err, ok := err.(*QueryError); ok {
+				errRes = err
 				return
 			}
 			panic(err)
@@ -109,7 +214,8 @@ func parseDocument(l *lexer.Lexer) *Document {
 	}
 	for l.Peek() != scanner.EOF {
 		if l.Peek() == '{' {
-			d.Operations[""] = &Operation{SelSet: parseSelectionSet(l)}
+			opLoc := loc(l)
+			d.Operations[""] = &Operation{SelSet: parseSelectionSet(l), Loc: opLoc}
 			continue
 		}
 
@@ -122,6 +228,10 @@ func parseDocument(l *lexer.Lexer) *Document {
 			q := parseOperation(l, Mutation)
 			d.Operations[q.Name] = q
 
+		case "subscription":
+			q := parseOperation(l, Subscription)
+			d.Operations[q.Name] = q
+
 		case "fragment":
 			f := parseFragment(l)
 			d.Fragments[f.Name] = f
@@ -133,45 +243,86 @@ func parseDocument(l *lexer.Lexer) *Document {
 	return d
 }
 
+func loc(l *lexer.Lexer) Loc {
+	pos := l.Location()
+	return Loc{Line: pos.Line, Column: pos.Column}
+}
+
 func parseOperation(l *lexer.Lexer, opType OperationType) *Operation {
-	op := &Operation{Type: opType}
+	op := &Operation{Type: opType, Loc: loc(l)}
 	if l.Peek() == scanner.Ident {
 		op.Name = l.ConsumeIdent()
 	}
 	if l.Peek() == '(' {
 		l.ConsumeToken('(')
 		op.Variables = make(map[string]*VariableDef)
-		for l.Peek() != ')' {
+		addVariableDef := func() {
 			v := parseVariableDef(l)
+			if _, found := op.Variables[v.Name]; found {
+				panic(errorf(v.Loc, "There can be only one variable named %q", v.Name))
+			}
 			op.Variables[v.Name] = v
 		}
+		if l.Peek() != ')' {
+			addVariableDef()
+			for l.Peek() != ')' {
+				l.ConsumeToken(',')
+				addVariableDef()
+			}
+		}
 		l.ConsumeToken(')')
 	}
+	for l.Peek() == '@' {
+		op.Directives = append(op.Directives, parseDirective(l))
+	}
 	op.SelSet = parseSelectionSet(l)
 	return op
 }
 
 func parseFragment(l *lexer.Lexer) *Fragment {
-	f := &Fragment{}
+	f := &Fragment{Loc: loc(l)}
 	f.Name = l.ConsumeIdent()
 	l.ConsumeKeyword("on")
 	f.On = l.ConsumeIdent()
+	for l.Peek() == '@' {
+		f.Directives = append(f.Directives, parseDirective(l))
+	}
 	f.SelSet = parseSelectionSet(l)
 	return f
 }
 
 func parseVariableDef(l *lexer.Lexer) *VariableDef {
-	v := &VariableDef{}
+	v := &VariableDef{Loc: loc(l)}
 	l.ConsumeToken('$')
 	v.Name = l.ConsumeIdent()
 	l.ConsumeToken(':')
-	v.Type = l.ConsumeIdent()
-	if l.Peek() == '!' {
-		l.ConsumeToken('!') // TODO
+	v.Type = parseType(l)
+	if l.Peek() == '=' {
+		l.ConsumeToken('=')
+		v.Default = parseValue(l)
+	}
+	for l.Peek() == '@' {
+		v.Directives = append(v.Directives, parseDirective(l))
 	}
 	return v
 }
 
+func parseType(l *lexer.Lexer) Type {
+	var t Type
+	if l.Peek() == '[' {
+		l.ConsumeToken('[')
+		t = ListType{OfType: parseType(l)}
+		l.ConsumeToken(']')
+	} else {
+		t = NamedType{Name: l.ConsumeIdent()}
+	}
+	if l.Peek() == '!' {
+		l.ConsumeToken('!')
+		t = NonNullType{OfType: t}
+	}
+	return t
+}
+
 func parseSelectionSet(l *lexer.Lexer) *SelectionSet {
 	sel := &SelectionSet{}
 	l.ConsumeToken('{')
@@ -190,9 +341,7 @@ func parseSelection(l *lexer.Lexer) Selection {
 }
 
 func parseField(l *lexer.Lexer) *Field {
-	f := &Field{
-		Directives: make(map[string]*Directive),
-	}
+	f := &Field{Loc: loc(l)}
 	f.Alias = l.ConsumeIdent()
 	f.Name = f.Alias
 	if l.Peek() == ':' {
@@ -203,8 +352,7 @@ func parseField(l *lexer.Lexer) *Field {
 		f.Arguments = parseArguments(l)
 	}
 	for l.Peek() == '@' {
-		d := parseDirective(l)
-		f.Directives[d.Name] = d
+		f.Directives = append(f.Directives, parseDirective(l))
 	}
 	if l.Peek() == '{' {
 		f.SelSet = parseSelectionSet(l)
@@ -212,24 +360,29 @@ func parseField(l *lexer.Lexer) *Field {
 	return f
 }
 
-func parseArguments(l *lexer.Lexer) map[string]Value {
-	args := make(map[string]Value)
+func parseArguments(l *lexer.Lexer) ArgumentList {
+	var args ArgumentList
 	l.ConsumeToken('(')
 	if l.Peek() != ')' {
-		name, value := parseArgument(l)
-		args[name] = value
+		args = append(args, parseArgument(l))
 		for l.Peek() != ')' {
 			l.ConsumeToken(',')
-			name, value := parseArgument(l)
-			args[name] = value
+			args = append(args, parseArgument(l))
 		}
 	}
 	l.ConsumeToken(')')
+	seen := make(map[string]bool, len(args))
+	for _, arg := range args {
+		if seen[arg.Name] {
+			panic(errorf(arg.Loc, "There can be only one argument named %q", arg.Name))
+		}
+		seen[arg.Name] = true
+	}
 	return args
 }
 
 func parseDirective(l *lexer.Lexer) *Directive {
-	d := &Directive{}
+	d := &Directive{Loc: loc(l)}
 	l.ConsumeToken('@')
 	d.Name = l.ConsumeIdent()
 	if l.Peek() == '(' {
@@ -239,25 +392,28 @@ func parseDirective(l *lexer.Lexer) *Directive {
 }
 
 func parseFragmentSpread(l *lexer.Lexer) *FragmentSpread {
-	fs := &FragmentSpread{
-		Directives: make(map[string]*Directive),
-	}
+	fs := &FragmentSpread{Loc: loc(l)}
 	l.ConsumeToken('.')
 	l.ConsumeToken('.')
 	l.ConsumeToken('.')
 	fs.Name = l.ConsumeIdent()
 	for l.Peek() == '@' {
-		d := parseDirective(l)
-		fs.Directives[d.Name] = d
+		fs.Directives = append(fs.Directives, parseDirective(l))
 	}
 	return fs
 }
 
-func parseArgument(l *lexer.Lexer) (string, Value) {
+// Block string literals (`"""..."""`) are not recognised. text/scanner reads
+// `"""` as an empty string followed by the opening quote of another string,
+// so a block string fails to parse; parseValue's scanner.String case handles
+// only ordinary `"..."` strings.
+
+func parseArgument(l *lexer.Lexer) Argument {
+	argLoc := loc(l)
 	name := l.ConsumeIdent()
 	l.ConsumeToken(':')
 	value := parseValue(l)
-	return name, value
+	return Argument{Name: name, Value: value, Loc: argLoc}
 }
 
 type ValueType int
@@ -277,14 +433,60 @@ func parseValue(l *lexer.Lexer) Value {
 		return &Variable{
 			Name: l.ConsumeIdent(),
 		}
+	case scanner.Int:
+		return &Literal{
+			Value: int64(l.ConsumeInt()),
+			Type:  Int,
+		}
+	case scanner.Float:
+		return &Literal{
+			Value: l.ConsumeFloat(),
+			Type:  Float,
+		}
 	case scanner.String:
 		return &Literal{
 			Value: l.ConsumeString(),
+			Type:  String,
 		}
 	case scanner.Ident:
-		return &Literal{
-			Value: l.ConsumeIdent(),
+		switch ident := l.ConsumeIdent(); ident {
+		case "true":
+			return &Literal{Value: true, Type: Boolean}
+		case "false":
+			return &Literal{Value: false, Type: Boolean}
+		case "null":
+			return &NullValue{}
+		default:
+			return &Literal{Value: ident, Type: Enum}
+		}
+	case '[':
+		l.ConsumeToken('[')
+		list := &ListValue{}
+		if l.Peek() != ']' {
+			list.Values = append(list.Values, parseValue(l))
+			for l.Peek() != ']' {
+				l.ConsumeToken(',')
+				list.Values = append(list.Values, parseValue(l))
+			}
+		}
+		l.ConsumeToken(']')
+		return list
+	case '{':
+		l.ConsumeToken('{')
+		obj := &ObjectValue{Fields: make(map[string]Value)}
+		if l.Peek() != '}' {
+			name := l.ConsumeIdent()
+			l.ConsumeToken(':')
+			obj.Fields[name] = parseValue(l)
+			for l.Peek() != '}' {
+				l.ConsumeToken(',')
+				name := l.ConsumeIdent()
+				l.ConsumeToken(':')
+				obj.Fields[name] = parseValue(l)
+			}
 		}
+		l.ConsumeToken('}')
+		return obj
 	default:
 		l.SyntaxError("invalid value")
 		panic("unreachable")
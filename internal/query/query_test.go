@@ -0,0 +1,215 @@
+package query
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSubscription(t *testing.T) {
+	doc, err := Parse(`subscription NewMessages { messageAdded }`)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	op, ok := doc.Operations["NewMessages"]
+	if !ok {
+		t.Fatal(`expected an operation named "NewMessages"`)
+	}
+	if op.Type != Subscription {
+		t.Errorf("got Type %v, want Subscription", op.Type)
+	}
+	if len(op.SelSet.Selections) != 1 {
+		t.Fatalf("got %d selections, want 1", len(op.SelSet.Selections))
+	}
+	field, ok := op.SelSet.Selections[0].(*Field)
+	if !ok || field.Name != "messageAdded" {
+		t.Errorf("got %#v, want field %q", op.SelSet.Selections[0], "messageAdded")
+	}
+}
+
+func TestParseVariableTypeAndDefaultValue(t *testing.T) {
+	doc, err := Parse(`query ($a: [Int!]! = [1, 2], $b: String = "hi") { foo }`)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	op := doc.Operations[""]
+	if op == nil {
+		t.Fatal("expected an anonymous operation")
+	}
+
+	a, ok := op.Variables["a"]
+	if !ok {
+		t.Fatal(`variable "a" missing`)
+	}
+	nonNull, ok := a.Type.(NonNullType)
+	if !ok {
+		t.Fatalf("got %#v, want a NonNullType", a.Type)
+	}
+	list, ok := nonNull.OfType.(ListType)
+	if !ok {
+		t.Fatalf("got %#v, want a ListType", nonNull.OfType)
+	}
+	elem, ok := list.OfType.(NonNullType)
+	if !ok {
+		t.Fatalf("got %#v, want a NonNullType", list.OfType)
+	}
+	named, ok := elem.OfType.(NamedType)
+	if !ok || named.Name != "Int" {
+		t.Errorf("got %#v, want NamedType{Int}", elem.OfType)
+	}
+	defaultList, ok := a.Default.(*ListValue)
+	if !ok || len(defaultList.Values) != 2 {
+		t.Fatalf("got %#v, want a 2-element default ListValue", a.Default)
+	}
+
+	b, ok := op.Variables["b"]
+	if !ok {
+		t.Fatal(`variable "b" missing`)
+	}
+	if named, ok := b.Type.(NamedType); !ok || named.Name != "String" {
+		t.Errorf("got %#v, want NamedType{String}", b.Type)
+	}
+	if lit, ok := b.Default.(*Literal); !ok || lit.Value != "hi" {
+		t.Errorf(`got %#v, want Literal{"hi"}`, b.Default)
+	}
+}
+
+func TestOperationLocIsPopulated(t *testing.T) {
+	doc, err := Parse("query Foo {\n  bar\n}")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	op, ok := doc.Operations["Foo"]
+	if !ok {
+		t.Fatal(`expected an operation named "Foo"`)
+	}
+	if op.Loc == (Loc{}) {
+		t.Error("got a zero-value Loc, want the operation's source location")
+	}
+}
+
+func TestQueryErrorIncludesLocation(t *testing.T) {
+	_, err := Parse(`query { foo(a: 1, a: 2) }`)
+	if err == nil {
+		t.Fatal("expected an error for a duplicate argument, got nil")
+	}
+	if !strings.Contains(err.Message, `There can be only one argument named "a"`) {
+		t.Errorf("got error %q, want it to mention the duplicate argument", err.Message)
+	}
+	if len(err.Locations) != 1 || err.Locations[0] == (Loc{}) {
+		t.Errorf("got Locations %#v, want a single non-zero Loc", err.Locations)
+	}
+}
+
+func TestParseDirectivesOnOperationFragmentAndVariable(t *testing.T) {
+	doc, err := Parse(`
+		query Foo($a: Int @varDir) @opDir {
+			...frag
+		}
+		fragment frag on Query @fragDir {
+			bar
+		}
+	`)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	op, ok := doc.Operations["Foo"]
+	if !ok {
+		t.Fatal(`expected an operation named "Foo"`)
+	}
+	if op.Directives.Get("opDir") == nil {
+		t.Error(`expected the operation to have an "opDir" directive`)
+	}
+	a, ok := op.Variables["a"]
+	if !ok {
+		t.Fatal(`variable "a" missing`)
+	}
+	if a.Directives.Get("varDir") == nil {
+		t.Error(`expected the variable definition to have a "varDir" directive`)
+	}
+
+	frag, ok := doc.Fragments["frag"]
+	if !ok {
+		t.Fatal(`expected a fragment named "frag"`)
+	}
+	if frag.Directives.Get("fragDir") == nil {
+		t.Error(`expected the fragment to have a "fragDir" directive`)
+	}
+}
+
+func TestParseMultipleVariableDefinitions(t *testing.T) {
+	doc, err := Parse(`query ($a: Int, $b: String) { foo }`)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	op := doc.Operations[""]
+	if op == nil {
+		t.Fatal("expected an anonymous operation")
+	}
+	if len(op.Variables) != 2 {
+		t.Fatalf("got %d variables, want 2", len(op.Variables))
+	}
+	if _, ok := op.Variables["a"]; !ok {
+		t.Error(`variable "a" missing`)
+	}
+	if _, ok := op.Variables["b"]; !ok {
+		t.Error(`variable "b" missing`)
+	}
+}
+
+func TestParseDuplicateVariableDefinition(t *testing.T) {
+	_, err := Parse(`query ($a: Int, $a: String) { foo }`)
+	if err == nil {
+		t.Fatal("expected an error for a duplicate variable name, got nil")
+	}
+	if !strings.Contains(err.Message, `There can be only one variable named "a"`) {
+		t.Errorf("got error %q, want it to mention the duplicate variable", err.Message)
+	}
+}
+
+func TestParseNestedListsAndObjectsWithVariables(t *testing.T) {
+	doc, err := Parse(`query ($v: String) { foo(a: [1, [2, 3], {x: $v, y: "s"}]) }`)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	field, ok := doc.Operations[""].SelSet.Selections[0].(*Field)
+	if !ok {
+		t.Fatal("expected the selection to be a field")
+	}
+	value, ok := field.Arguments.Get("a")
+	if !ok {
+		t.Fatal(`expected an "a" argument`)
+	}
+
+	list, ok := value.(*ListValue)
+	if !ok || len(list.Values) != 3 {
+		t.Fatalf("got %#v, want a 3-element ListValue", value)
+	}
+
+	if lit, ok := list.Values[0].(*Literal); !ok || lit.Value != int64(1) {
+		t.Errorf("got %#v, want Literal{1}", list.Values[0])
+	}
+
+	inner, ok := list.Values[1].(*ListValue)
+	if !ok || len(inner.Values) != 2 {
+		t.Fatalf("got %#v, want a nested 2-element ListValue", list.Values[1])
+	}
+	if lit, ok := inner.Values[0].(*Literal); !ok || lit.Value != int64(2) {
+		t.Errorf("got %#v, want Literal{2}", inner.Values[0])
+	}
+	if lit, ok := inner.Values[1].(*Literal); !ok || lit.Value != int64(3) {
+		t.Errorf("got %#v, want Literal{3}", inner.Values[1])
+	}
+
+	obj, ok := list.Values[2].(*ObjectValue)
+	if !ok || len(obj.Fields) != 2 {
+		t.Fatalf("got %#v, want a 2-field ObjectValue", list.Values[2])
+	}
+	v, ok := obj.Fields["x"].(*Variable)
+	if !ok || v.Name != "v" {
+		t.Errorf(`got %#v, want Variable{"v"}`, obj.Fields["x"])
+	}
+	if lit, ok := obj.Fields["y"].(*Literal); !ok || lit.Value != "s" {
+		t.Errorf(`got %#v, want Literal{"s"}`, obj.Fields["y"])
+	}
+}